@@ -0,0 +1,318 @@
+package lfshook
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DropPolicy controls what happens when an async destination's queue is
+// full.
+type DropPolicy int
+
+const (
+	// Block makes Fire wait for room in the queue, preserving ordering and
+	// back-pressuring the caller.
+	Block DropPolicy = iota
+	// DropNewest discards the entry that just arrived, keeping whatever is
+	// already queued.
+	DropNewest
+	// DropOldest discards the oldest queued entry to make room for the new
+	// one.
+	DropOldest
+	// LogAndDrop behaves like DropNewest but also logs the drop via the
+	// standard logger, for callers who want visibility without failing Fire.
+	LogAndDrop
+)
+
+// AsyncOptions configures the background writer used by async mode.
+type AsyncOptions struct {
+	// BufferSize is the capacity of each destination's queue.
+	BufferSize int
+
+	// Policy decides what happens when a destination's queue is full.
+	Policy DropPolicy
+
+	// FlushInterval is how often a destination flushes a partial batch,
+	// regardless of BatchSize. Zero disables timed flushing: a partial
+	// batch is then flushed only once it reaches BatchSize, or by Flush or
+	// Close.
+	FlushInterval time.Duration
+
+	// BatchSize is how many queued messages are written together before
+	// the destination's writer is given a chance to flush. Zero means 1.
+	BatchSize int
+}
+
+// Stats reports the lifetime counters for a hook's async destinations.
+type Stats struct {
+	Queued  uint64
+	Flushed uint64
+	Dropped uint64
+}
+
+// asyncJob is a fully formatted entry waiting to be written to dest. A job
+// with a non-nil flushDone is a flush request rather than a log line: drain
+// flushes dest's current batch and closes flushDone, without counting it
+// against Stats.Flushed.
+type asyncJob struct {
+	key       string
+	msg       []byte
+	flushDone chan struct{}
+}
+
+// asyncDest is the background drain loop for a single writer.
+type asyncDest struct {
+	writer io.Writer
+	queue  chan asyncJob
+	done   chan struct{}
+}
+
+type asyncState struct {
+	opts AsyncOptions
+
+	lock        sync.Mutex
+	dests       map[string]*asyncDest
+	fileHandles map[string]*os.File
+
+	// closeLock guards against Close() closing a destination's queue
+	// concurrently with enqueue() sending on it: enqueue holds a read lock
+	// for the whole lookup-then-send, and Close takes the write lock
+	// before closing any channel, so the two can never interleave.
+	closeLock sync.RWMutex
+	closed    bool
+
+	queued  uint64
+	flushed uint64
+	dropped uint64
+
+	wg sync.WaitGroup
+}
+
+// NewAsyncHook builds a Hook whose Fire enqueues formatted entries instead of
+// writing them inline, draining them from a background goroutine per
+// destination. Callers must call Close or Flush to guarantee queued entries
+// reach disk before the process exits.
+func NewAsyncHook(levelMap interface{}, userFormatter logrus.Formatter, opts AsyncOptions) *lfsHook {
+	hook := NewHook(levelMap, userFormatter)
+	hook.EnableAsync(opts)
+	return hook
+}
+
+// EnableAsync turns an existing hook into an async one. It is not safe to
+// call concurrently with Fire.
+func (hook *lfsHook) EnableAsync(opts AsyncOptions) {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 1024
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1
+	}
+
+	hook.async = &asyncState{
+		opts:  opts,
+		dests: make(map[string]*asyncDest),
+	}
+}
+
+// Stats returns a snapshot of the hook's async counters. It returns a zero
+// Stats if the hook is not running in async mode.
+func (hook *lfsHook) Stats() Stats {
+	if hook.async == nil {
+		return Stats{}
+	}
+	return Stats{
+		Queued:  atomic.LoadUint64(&hook.async.queued),
+		Flushed: atomic.LoadUint64(&hook.async.flushed),
+		Dropped: atomic.LoadUint64(&hook.async.dropped),
+	}
+}
+
+// Close stops all background drain goroutines after their queues have
+// drained. The hook stays in async mode: entries fired after Close are
+// dropped and counted in Stats.Dropped.
+func (hook *lfsHook) Close() error {
+	if hook.async == nil {
+		return nil
+	}
+
+	hook.async.closeLock.Lock()
+	hook.async.closed = true
+	hook.async.lock.Lock()
+	dests := hook.async.dests
+	hook.async.dests = make(map[string]*asyncDest)
+	hook.async.lock.Unlock()
+	hook.async.closeLock.Unlock()
+
+	for _, dest := range dests {
+		close(dest.queue)
+	}
+	hook.async.wg.Wait()
+
+	hook.async.lock.Lock()
+	for _, fd := range hook.async.fileHandles {
+		fd.Close()
+	}
+	hook.async.fileHandles = nil
+	hook.async.lock.Unlock()
+
+	return nil
+}
+
+// Flush blocks until every destination that existed at the time of the call
+// has processed everything queued ahead of this call and actually flushed
+// it (calling the destination's Flush() if it has one), or until ctx is
+// done.
+func (hook *lfsHook) Flush(ctx context.Context) error {
+	if hook.async == nil {
+		return nil
+	}
+
+	hook.async.closeLock.RLock()
+	defer hook.async.closeLock.RUnlock()
+
+	if hook.async.closed {
+		return nil
+	}
+
+	hook.async.lock.Lock()
+	dests := make([]*asyncDest, 0, len(hook.async.dests))
+	for _, dest := range hook.async.dests {
+		dests = append(dests, dest)
+	}
+	hook.async.lock.Unlock()
+
+	for _, dest := range dests {
+		done := make(chan struct{})
+		select {
+		case dest.queue <- asyncJob{flushDone: done}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// enqueue routes a formatted message to the destination identified by key,
+// starting its drain goroutine on first use.
+func (hook *lfsHook) enqueue(key string, writer io.Writer, msg []byte) {
+	async := hook.async
+
+	async.closeLock.RLock()
+	defer async.closeLock.RUnlock()
+
+	if async.closed {
+		atomic.AddUint64(&async.dropped, 1)
+		return
+	}
+
+	async.lock.Lock()
+	dest, ok := async.dests[key]
+	if !ok {
+		dest = &asyncDest{
+			writer: writer,
+			queue:  make(chan asyncJob, async.opts.BufferSize),
+			done:   make(chan struct{}),
+		}
+		async.dests[key] = dest
+		async.wg.Add(1)
+		go async.drain(dest)
+	}
+	async.lock.Unlock()
+
+	job := asyncJob{key: key, msg: msg}
+
+	if async.opts.Policy == Block {
+		dest.queue <- job
+		atomic.AddUint64(&async.queued, 1)
+		return
+	}
+
+	select {
+	case dest.queue <- job:
+		atomic.AddUint64(&async.queued, 1)
+	default:
+		switch async.opts.Policy {
+		case DropOldest:
+			select {
+			case <-dest.queue:
+				atomic.AddUint64(&async.dropped, 1)
+			default:
+			}
+			select {
+			case dest.queue <- job:
+				atomic.AddUint64(&async.queued, 1)
+			default:
+				atomic.AddUint64(&async.dropped, 1)
+			}
+		case LogAndDrop:
+			log.Println("lfshook: dropping log entry, queue full for", key)
+			atomic.AddUint64(&async.dropped, 1)
+		default: // DropNewest
+			atomic.AddUint64(&async.dropped, 1)
+		}
+	}
+}
+
+// drain batches jobs for dest until its queue is closed, flushing whatever
+// partial batch remains before it returns so Close always delivers queued
+// entries regardless of BatchSize.
+func (state *asyncState) drain(dest *asyncDest) {
+	defer state.wg.Done()
+	defer close(dest.done)
+
+	batch := 0
+	flush := func() {
+		if f, ok := dest.writer.(interface{ Flush() error }); ok {
+			f.Flush()
+		}
+		batch = 0
+	}
+
+	var flushTimer <-chan time.Time
+	if state.opts.FlushInterval > 0 {
+		ticker := time.NewTicker(state.opts.FlushInterval)
+		defer ticker.Stop()
+		flushTimer = ticker.C
+	}
+
+	for {
+		select {
+		case job, ok := <-dest.queue:
+			if !ok {
+				if batch > 0 {
+					flush()
+				}
+				return
+			}
+			if job.flushDone != nil {
+				flush()
+				close(job.flushDone)
+				continue
+			}
+			dest.writer.Write(job.msg)
+			atomic.AddUint64(&state.flushed, 1)
+			batch++
+			if batch >= state.opts.BatchSize {
+				flush()
+			}
+		case <-flushTimer:
+			if batch > 0 {
+				flush()
+			}
+		}
+	}
+}
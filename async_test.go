@@ -0,0 +1,140 @@
+package lfshook
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Len()
+}
+
+func TestAsyncHookWritesAndCloses(t *testing.T) {
+	buf := &syncBuffer{}
+	hook := NewAsyncHook(WriterMap{logrus.InfoLevel: buf}, nil, AsyncOptions{BufferSize: 8})
+
+	entry := &logrus.Entry{Logger: logrus.New(), Level: logrus.InfoLevel, Message: "hello"}
+	if err := hook.Fire(entry); err != nil {
+		t.Fatalf("Fire returned error: %v", err)
+	}
+
+	if err := hook.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	if buf.Len() == 0 {
+		t.Fatal("expected the entry to have been written before Close returned")
+	}
+
+	stats := hook.Stats()
+	if stats.Flushed != 1 {
+		t.Fatalf("expected 1 flushed entry, got %d", stats.Flushed)
+	}
+}
+
+func TestAsyncHookDropNewestWhenFull(t *testing.T) {
+	buf := &syncBuffer{}
+	hook := NewAsyncHook(WriterMap{logrus.InfoLevel: buf}, nil, AsyncOptions{BufferSize: 1, Policy: DropNewest})
+
+	// Fire enough entries, back to back and unsynchronized with the drain
+	// goroutine, that the 1-slot queue has to be full for at least one of
+	// them.
+	logger := logrus.New()
+	for i := 0; i < 100; i++ {
+		entry := &logrus.Entry{Logger: logger, Level: logrus.InfoLevel, Message: "a"}
+		hook.Fire(entry)
+	}
+
+	hook.Close()
+
+	stats := hook.Stats()
+	if stats.Dropped == 0 {
+		t.Fatalf("expected at least one dropped entry with a 1-slot buffer, got %+v", stats)
+	}
+}
+
+func TestAsyncHookCloseFlushesPartialBatch(t *testing.T) {
+	var underlying bytes.Buffer
+	bufWriter := bufio.NewWriter(&underlying)
+	hook := NewAsyncHook(WriterMap{logrus.InfoLevel: bufWriter}, nil, AsyncOptions{BufferSize: 8, BatchSize: 10})
+
+	logger := logrus.New()
+	for i := 0; i < 3; i++ {
+		entry := &logrus.Entry{Logger: logger, Level: logrus.InfoLevel, Message: "partial"}
+		if err := hook.Fire(entry); err != nil {
+			t.Fatalf("Fire returned error: %v", err)
+		}
+	}
+
+	if err := hook.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	if underlying.Len() == 0 {
+		t.Fatal("expected Close to flush a batch smaller than BatchSize, but nothing reached the underlying writer")
+	}
+}
+
+func TestAsyncHookFlushForcesPartialBatch(t *testing.T) {
+	var underlying bytes.Buffer
+	bufWriter := bufio.NewWriter(&underlying)
+	hook := NewAsyncHook(WriterMap{logrus.InfoLevel: bufWriter}, nil, AsyncOptions{BufferSize: 8, BatchSize: 10})
+	defer hook.Close()
+
+	logger := logrus.New()
+	entry := &logrus.Entry{Logger: logger, Level: logrus.InfoLevel, Message: "flush me"}
+	if err := hook.Fire(entry); err != nil {
+		t.Fatalf("Fire returned error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := hook.Flush(ctx); err != nil {
+		t.Fatalf("Flush returned error: %v", err)
+	}
+
+	if underlying.Len() == 0 {
+		t.Fatal("expected Flush to force a partial batch through to the underlying writer")
+	}
+}
+
+func TestAsyncHookConcurrentFireAndClose(t *testing.T) {
+	buf := &syncBuffer{}
+	hook := NewAsyncHook(WriterMap{logrus.InfoLevel: buf}, nil, AsyncOptions{BufferSize: 64, Policy: Block})
+
+	var wg sync.WaitGroup
+	logger := logrus.New()
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			entry := &logrus.Entry{Logger: logger, Level: logrus.InfoLevel, Message: "concurrent"}
+			hook.Fire(entry)
+		}()
+	}
+
+	time.Sleep(time.Millisecond)
+	if err := hook.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	wg.Wait()
+}
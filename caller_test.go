@@ -0,0 +1,46 @@
+package lfshook
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestCallerEnrichmentFindsApplicationFrame(t *testing.T) {
+	buf := &syncBuffer{}
+	hook := NewHook(WriterMap{logrus.InfoLevel: buf}, nil)
+	hook.SetCallerConfig(CallerConfig{})
+
+	entry := &logrus.Entry{Logger: logrus.New(), Level: logrus.InfoLevel, Message: "hi", Data: logrus.Fields{}}
+	if err := hook.Fire(entry); err != nil {
+		t.Fatalf("Fire returned error: %v", err)
+	}
+
+	caller, ok := entry.Data["caller"].(string)
+	if !ok || caller == "" {
+		t.Fatalf("expected entry.Data[%q] to be populated, got %#v", "caller", entry.Data["caller"])
+	}
+	for _, internal := range append([]string{"sirupsen/logrus."}, internalFrameSuffixes...) {
+		if strings.Contains(caller, internal) {
+			t.Fatalf("expected caller to point at the test, not internal plumbing (%s): %s", internal, caller)
+		}
+	}
+	if !strings.Contains(caller, "TestCallerEnrichmentFindsApplicationFrame") {
+		t.Fatalf("expected caller to name this test function, got %s", caller)
+	}
+}
+
+func TestCallerEnrichmentDisabledIsNoop(t *testing.T) {
+	buf := &syncBuffer{}
+	hook := NewHook(WriterMap{logrus.InfoLevel: buf}, nil)
+
+	entry := &logrus.Entry{Logger: logrus.New(), Level: logrus.InfoLevel, Message: "hi", Data: logrus.Fields{}}
+	if err := hook.Fire(entry); err != nil {
+		t.Fatalf("Fire returned error: %v", err)
+	}
+
+	if _, ok := entry.Data["caller"]; ok {
+		t.Fatal("expected no caller field when CallerConfig is not set")
+	}
+}
@@ -34,6 +34,18 @@ type lfsHook struct {
 	defaultWriter    io.Writer
 	hasDefaultPath   bool
 	hasDefaultWriter bool
+
+	// async is non-nil once EnableAsync/NewAsyncHook has put the hook into
+	// async mode; see async.go.
+	async *asyncState
+
+	// caller is non-nil once SetCallerConfig has enabled caller
+	// enrichment; see caller.go.
+	caller *CallerConfig
+
+	// filters are run in order before any write; an entry is dropped as
+	// soon as one of them returns false.
+	filters []func(*logrus.Entry) bool
 }
 
 // Given a map with keys equal to log levels.
@@ -70,6 +82,15 @@ func NewHook(levelMap interface{}, userFormatter logrus.Formatter) *lfsHook {
 		panic(fmt.Sprintf("unsupported level map type: %s", reflect.TypeOf(levelMap)))
 	}
 
+	// A nil levelMap (the documented SetDefaultPath/SetDefaultWriter-only
+	// pattern) or an empty PathMap/WriterMap leaves hook.levels empty,
+	// which logrus's LevelHooks.Add would register for zero levels. Fall
+	// back to all levels so the hook still fires for everything until
+	// SetLevels narrows it down.
+	if len(hook.levels) == 0 {
+		hook.levels = logrus.AllLevels
+	}
+
 	return hook
 }
 
@@ -96,9 +117,46 @@ func (hook *lfsHook) SetDefaultWriter(defaultWriter io.Writer) {
 	hook.hasDefaultWriter = true
 }
 
+// SetLevels replaces the levels this hook is registered for, overriding
+// whatever NewHook inferred from its PathMap/WriterMap keys.
+func (hook *lfsHook) SetLevels(levels []logrus.Level) {
+	hook.levels = levels
+}
+
+// WithMinLevel expands a single threshold into the slice of levels at least
+// as severe as min, suitable for passing to SetLevels, e.g.
+// hook.SetLevels(lfshook.WithMinLevel(logrus.InfoLevel)) to drop Debug and
+// Trace entries.
+func WithMinLevel(min logrus.Level) []logrus.Level {
+	levels := []logrus.Level{}
+	for _, level := range logrus.AllLevels {
+		if level <= min {
+			levels = append(levels, level)
+		}
+	}
+	return levels
+}
+
+// AddFilter registers a predicate that is run before every write; an entry
+// is dropped as soon as one filter returns false. Filters run in the order
+// they were added.
+func (hook *lfsHook) AddFilter(filter func(*logrus.Entry) bool) {
+	hook.filters = append(hook.filters, filter)
+}
+
 // Open the file, write to the file, close the file.
 // Whichever user is running the function needs write permissions to the file or directory if the file does not yet exist.
 func (hook *lfsHook) Fire(entry *logrus.Entry) error {
+	for _, filter := range hook.filters {
+		if !filter(entry) {
+			return nil
+		}
+	}
+
+	if hook.caller != nil {
+		entry.Data[hook.caller.Field] = hook.resolveCaller()
+	}
+
 	if hook.writers != nil || hook.hasDefaultWriter {
 		return hook.ioWrite(entry)
 	} else if hook.paths != nil || hook.hasDefaultPath {
@@ -135,6 +193,12 @@ func (hook *lfsHook) ioWrite(entry *logrus.Entry) error {
 		log.Println("failed to generate string for entry:", err)
 		return err
 	}
+
+	if hook.async != nil {
+		hook.enqueue(fmt.Sprintf("%p", writer), writer, msg)
+		return nil
+	}
+
 	_, err = writer.Write(msg)
 	return err
 }
@@ -160,6 +224,24 @@ func (hook *lfsHook) fileWrite(entry *logrus.Entry) error {
 		}
 	}
 
+	// use our formatter instead of entry.String()
+	msg, err = hook.formatter.Format(entry)
+
+	if err != nil {
+		log.Println("failed to generate string for entry:", err)
+		return err
+	}
+
+	if hook.async != nil {
+		fd, err = hook.openPath(path)
+		if err != nil {
+			log.Println("failed to open logfile:", path, err)
+			return err
+		}
+		hook.enqueue(path, fd, msg)
+		return nil
+	}
+
 	dir := filepath.Dir(path)
 	os.MkdirAll(dir, os.ModePerm)
 
@@ -170,18 +252,37 @@ func (hook *lfsHook) fileWrite(entry *logrus.Entry) error {
 	}
 	defer fd.Close()
 
-	// use our formatter instead of entry.String()
-	msg, err = hook.formatter.Format(entry)
+	fd.Write(msg)
+	return nil
+}
 
+// openPath opens path once and keeps it open for reuse by async mode, which
+// writes from a background goroutine instead of on every Fire call.
+func (hook *lfsHook) openPath(path string) (*os.File, error) {
+	hook.async.lock.Lock()
+	defer hook.async.lock.Unlock()
+
+	if fd, ok := hook.async.fileHandles[path]; ok {
+		return fd, nil
+	}
+
+	dir := filepath.Dir(path)
+	os.MkdirAll(dir, os.ModePerm)
+
+	fd, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
 	if err != nil {
-		log.Println("failed to generate string for entry:", err)
-		return err
+		return nil, err
 	}
-	fd.Write(msg)
-	return nil
+
+	if hook.async.fileHandles == nil {
+		hook.async.fileHandles = make(map[string]*os.File)
+	}
+	hook.async.fileHandles[path] = fd
+	return fd, nil
 }
 
-// Levels returns configured log levels.
+// Levels returns the levels this hook is registered for, so logrus only
+// calls Fire for entries the hook actually handles.
 func (hook *lfsHook) Levels() []logrus.Level {
-	return logrus.AllLevels
+	return hook.levels
 }
@@ -0,0 +1,65 @@
+package lfshook
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestLevelsDefaultsToAllLevelsForDefaultOnlyHook(t *testing.T) {
+	hook := NewHook(nil, nil)
+	hook.SetDefaultWriter(&syncBuffer{})
+
+	levels := hook.Levels()
+	if len(levels) != len(logrus.AllLevels) {
+		t.Fatalf("expected a default-writer-only hook to be registered for all %d levels, got %d: %v", len(logrus.AllLevels), len(levels), levels)
+	}
+}
+
+func TestLevelsReflectsWriterMap(t *testing.T) {
+	hook := NewHook(WriterMap{logrus.ErrorLevel: &syncBuffer{}, logrus.InfoLevel: &syncBuffer{}}, nil)
+
+	levels := hook.Levels()
+	if len(levels) != 2 {
+		t.Fatalf("expected 2 levels, got %d: %v", len(levels), levels)
+	}
+}
+
+func TestSetLevelsAndWithMinLevel(t *testing.T) {
+	hook := NewHook(WriterMap{logrus.InfoLevel: &syncBuffer{}}, nil)
+	hook.SetLevels(WithMinLevel(logrus.WarnLevel))
+
+	for _, level := range hook.Levels() {
+		if level > logrus.WarnLevel {
+			t.Fatalf("WithMinLevel(WarnLevel) should not include %s", level)
+		}
+	}
+	if len(hook.Levels()) != 4 { // Panic, Fatal, Error, Warn
+		t.Fatalf("expected 4 levels at or above WarnLevel, got %d: %v", len(hook.Levels()), hook.Levels())
+	}
+}
+
+func TestAddFilterDropsEntry(t *testing.T) {
+	buf := &syncBuffer{}
+	hook := NewHook(WriterMap{logrus.InfoLevel: buf}, nil)
+	hook.AddFilter(func(entry *logrus.Entry) bool {
+		_, ok := entry.Data["request_id"]
+		return ok
+	})
+
+	dropped := &logrus.Entry{Logger: logrus.New(), Level: logrus.InfoLevel, Message: "no id", Data: logrus.Fields{}}
+	if err := hook.Fire(dropped); err != nil {
+		t.Fatalf("Fire returned error: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("expected entry without request_id to be dropped, but something was written")
+	}
+
+	kept := &logrus.Entry{Logger: logrus.New(), Level: logrus.InfoLevel, Message: "has id", Data: logrus.Fields{"request_id": "abc"}}
+	if err := hook.Fire(kept); err != nil {
+		t.Fatalf("Fire returned error: %v", err)
+	}
+	if buf.Len() == 0 {
+		t.Fatal("expected entry with request_id to be written")
+	}
+}
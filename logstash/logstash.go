@@ -0,0 +1,185 @@
+// Package logstash provides a Logstash v1 JSON formatter and reconnecting
+// TCP/UDP writers, for use as a lfshook.WriterMap destination.
+package logstash
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Formatter emits Logstash v1 JSON events: "@timestamp", "@version",
+// "message", "level" plus the entry's fields flattened alongside them.
+type Formatter struct {
+	// Type is written as the "type" field on every event. Empty leaves it
+	// out.
+	Type string
+
+	// TimestampFormat overrides the "@timestamp" layout. Empty uses
+	// time.RFC3339Nano.
+	TimestampFormat string
+}
+
+// Format implements logrus.Formatter.
+func (f *Formatter) Format(entry *logrus.Entry) ([]byte, error) {
+	layout := f.TimestampFormat
+	if layout == "" {
+		layout = time.RFC3339Nano
+	}
+
+	fields := make(logrus.Fields, len(entry.Data)+4)
+	for k, v := range entry.Data {
+		fields[k] = v
+	}
+
+	fields["@timestamp"] = entry.Time.Format(layout)
+	fields["@version"] = "1"
+	fields["message"] = entry.Message
+	fields["level"] = entry.Level.String()
+	if f.Type != "" {
+		fields["type"] = f.Type
+	}
+
+	msg, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("logstash: failed to marshal fields: %v", err)
+	}
+
+	return append(msg, '\n'), nil
+}
+
+// SpoolOptions enables writing to a local file when the remote Logstash
+// endpoint is unreachable, using the same open/append/close approach as
+// lfshook's own file writer.
+type SpoolOptions struct {
+	// Path is the file entries are appended to while the connection is
+	// down. Empty disables spooling; unsent entries are simply dropped.
+	Path string
+}
+
+func (s SpoolOptions) write(p []byte) {
+	if s.Path == "" {
+		return
+	}
+
+	dir := filepath.Dir(s.Path)
+	os.MkdirAll(dir, os.ModePerm)
+
+	fd, err := os.OpenFile(s.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+	if err != nil {
+		return
+	}
+	defer fd.Close()
+	fd.Write(p)
+}
+
+// reconnectWriter is the shared implementation behind NewTCPWriter and
+// NewUDPWriter: it keeps a connection open, reconnecting with backoff on
+// failure, and spools to disk when no connection is available.
+type reconnectWriter struct {
+	network string
+	addr    string
+	tlsCfg  *tls.Config
+	spool   SpoolOptions
+
+	lock        sync.Mutex
+	conn        net.Conn
+	backoff     time.Duration
+	nextAttempt time.Time
+}
+
+const (
+	minBackoff = 250 * time.Millisecond
+	maxBackoff = 30 * time.Second
+)
+
+// NewTCPWriter returns a reconnecting io.Writer that ships lines to a
+// Logstash TCP input. tlsConfig may be nil for a plaintext connection.
+// Entries are spooled to spool.Path while the connection is down.
+func NewTCPWriter(addr string, tlsConfig *tls.Config, spool SpoolOptions) *reconnectWriter {
+	return &reconnectWriter{network: "tcp", addr: addr, tlsCfg: tlsConfig, spool: spool, backoff: minBackoff}
+}
+
+// NewUDPWriter returns an io.Writer that ships lines to a Logstash UDP
+// input. It shares the TCP writer's dial, backoff and spooling: a failed
+// dial or write spools the line, and the next dial waits out the backoff.
+func NewUDPWriter(addr string, spool SpoolOptions) *reconnectWriter {
+	return &reconnectWriter{network: "udp", addr: addr, spool: spool, backoff: minBackoff}
+}
+
+// Write implements io.Writer, dialing lazily and spooling to disk on
+// failure.
+func (w *reconnectWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.conn == nil {
+		if err := w.dial(); err != nil {
+			w.spool.write(p)
+			return len(p), nil
+		}
+	}
+
+	if _, err := w.conn.Write(p); err != nil {
+		w.conn.Close()
+		w.conn = nil
+		w.spool.write(p)
+		return len(p), nil
+	}
+
+	w.backoff = minBackoff
+	return len(p), nil
+}
+
+// dial connects, honoring the writer's current backoff as a deadline rather
+// than a sleep: Write holds w.lock for the whole call, so blocking here
+// would stall every other goroutine logging through this writer (and, in
+// async mode, the drain goroutine) until the dial either succeeds or times
+// out.
+func (w *reconnectWriter) dial() error {
+	if now := time.Now(); now.Before(w.nextAttempt) {
+		return fmt.Errorf("logstash: %s unreachable, next attempt at %s", w.addr, w.nextAttempt)
+	}
+
+	var conn net.Conn
+	var err error
+
+	if w.network == "tcp" && w.tlsCfg != nil {
+		conn, err = tls.Dial(w.network, w.addr, w.tlsCfg)
+	} else {
+		conn, err = net.Dial(w.network, w.addr)
+	}
+
+	if err != nil {
+		w.nextAttempt = time.Now().Add(w.backoff)
+		w.backoff *= 2
+		if w.backoff > maxBackoff {
+			w.backoff = maxBackoff
+		}
+		return err
+	}
+
+	w.conn = conn
+	w.nextAttempt = time.Time{}
+	return nil
+}
+
+// Close closes the underlying connection, if any.
+func (w *reconnectWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
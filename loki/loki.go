@@ -0,0 +1,344 @@
+// Package loki provides an io.Writer that batches log lines to Grafana
+// Loki's push API, for use as a lfshook.WriterMap destination.
+package loki
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// LokiConfig configures a Loki push-API writer.
+type LokiConfig struct {
+	// PushURL is the full push endpoint, e.g.
+	// "http://localhost:3100/loki/api/v1/push".
+	PushURL string
+
+	// LabelAllowlist restricts which logrus fields become Loki stream
+	// labels; fields not in this set are kept in the line body instead,
+	// to avoid high-cardinality label explosions. A nil allowlist means
+	// no fields are promoted to labels beyond "level".
+	LabelAllowlist []string
+
+	// StaticLabels are attached to every stream in addition to whatever is
+	// promoted from LabelAllowlist.
+	StaticLabels map[string]string
+
+	// BatchSize is how many lines accumulate before a push is triggered.
+	// Zero defaults to 100.
+	BatchSize int
+
+	// BatchWait is the maximum time a partial batch waits before being
+	// pushed anyway. Zero defaults to 1s.
+	BatchWait time.Duration
+
+	// BufferSize bounds how many lines may be queued before new lines are
+	// dropped. Zero defaults to 10 * BatchSize.
+	BufferSize int
+
+	// MaxRetries caps the number of retries for a failed push, each with
+	// exponential backoff starting at RetryBaseDelay. Zero defaults to 3.
+	MaxRetries int
+
+	// RetryBaseDelay is the initial backoff delay. Zero defaults to
+	// 500ms.
+	RetryBaseDelay time.Duration
+
+	// BasicAuthUser/BasicAuthPass enable HTTP basic auth on push requests.
+	BasicAuthUser string
+	BasicAuthPass string
+
+	// BearerToken, if set, is sent as "Authorization: Bearer <token>".
+	BearerToken string
+
+	// TenantID, if set, is sent as the "X-Scope-OrgID" header.
+	TenantID string
+
+	// Client is the HTTP client used to push batches. http.DefaultClient
+	// is used when nil.
+	Client *http.Client
+}
+
+type line struct {
+	labels   string
+	labelSet map[string]string
+	ts       time.Time
+	msg      string
+}
+
+// Writer batches entries and pushes them to Loki. It implements io.Writer so
+// it can be used directly as a lfshook.WriterMap value.
+type Writer struct {
+	cfg LokiConfig
+
+	queue chan line
+
+	// closeLock guards against Close() closing queue concurrently with
+	// Write() sending on it: Write holds a read lock for its whole send,
+	// and Close takes the write lock before closing the channel, so the
+	// two can never interleave.
+	closeLock sync.RWMutex
+	closed    bool
+
+	wg sync.WaitGroup
+}
+
+// NewLokiWriter builds a Writer and starts its background batching
+// goroutine.
+func NewLokiWriter(cfg LokiConfig) *Writer {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.BatchWait <= 0 {
+		cfg.BatchWait = time.Second
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = cfg.BatchSize * 10
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.RetryBaseDelay <= 0 {
+		cfg.RetryBaseDelay = 500 * time.Millisecond
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+
+	w := &Writer{
+		cfg:   cfg,
+		queue: make(chan line, cfg.BufferSize),
+	}
+
+	w.wg.Add(1)
+	go w.run()
+
+	return w
+}
+
+// Write accepts one already-formatted logrus entry. The entry is expected to
+// be JSON (e.g. logrus.JSONFormatter output) so that fields named in
+// LabelAllowlist can be promoted to Loki stream labels; entries that fail to
+// parse as JSON are shipped as a single line carrying only StaticLabels.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.closeLock.RLock()
+	defer w.closeLock.RUnlock()
+
+	if w.closed {
+		return 0, fmt.Errorf("loki: writer is closed")
+	}
+
+	entry := make(map[string]interface{})
+	labels := map[string]string{}
+	for k, v := range w.cfg.StaticLabels {
+		labels[k] = v
+	}
+
+	if err := json.Unmarshal(p, &entry); err == nil {
+		allow := make(map[string]bool, len(w.cfg.LabelAllowlist))
+		for _, k := range w.cfg.LabelAllowlist {
+			allow[k] = true
+		}
+		if lvl, ok := entry["level"]; ok {
+			labels["level"] = fmt.Sprintf("%v", lvl)
+		}
+		for k, v := range entry {
+			if allow[k] {
+				labels[k] = fmt.Sprintf("%v", v)
+			}
+		}
+	}
+
+	l := line{labels: labelKey(labels), labelSet: labels, ts: time.Now(), msg: string(p)}
+
+	select {
+	case w.queue <- l:
+	default:
+		return 0, fmt.Errorf("loki: buffer full, dropping line")
+	}
+
+	return len(p), nil
+}
+
+// labelKey renders labels as a deterministic Loki stream selector string.
+func labelKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[k]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// run drains the queue into batches and pushes them on BatchWait/BatchSize.
+func (w *Writer) run() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.cfg.BatchWait)
+	defer ticker.Stop()
+
+	var batch []line
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := w.push(batch); err != nil {
+			log.Println("loki: push failed:", err)
+		}
+		batch = nil
+	}
+
+	for {
+		select {
+		case l, ok := <-w.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, l)
+			if len(batch) >= w.cfg.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// push sends one batch to the Loki push API, gzip-compressed, retrying with
+// exponential backoff.
+func (w *Writer) push(batch []line) error {
+	streams := map[string][]line{}
+	for _, l := range batch {
+		streams[l.labels] = append(streams[l.labels], l)
+	}
+
+	payload := struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}{}
+
+	for key, lines := range streams {
+		entry := struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		}{Stream: lines[0].labelSet}
+		for _, l := range lines {
+			entry.Values = append(entry.Values, [2]string{
+				strconv.FormatInt(l.ts.UnixNano(), 10),
+				l.msg,
+			})
+		}
+		payload.Streams = append(payload.Streams, entry)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	var gzBody bytes.Buffer
+	gz := gzip.NewWriter(&gzBody)
+	if _, err := gz.Write(body); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	delay := w.cfg.RetryBaseDelay
+	var lastErr error
+	for attempt := 0; attempt <= w.cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+
+		req, err := http.NewRequest(http.MethodPost, w.cfg.PushURL, bytes.NewReader(gzBody.Bytes()))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", "gzip")
+		if w.cfg.TenantID != "" {
+			req.Header.Set("X-Scope-OrgID", w.cfg.TenantID)
+		}
+		if w.cfg.BearerToken != "" {
+			req.Header.Set("Authorization", "Bearer "+w.cfg.BearerToken)
+		} else if w.cfg.BasicAuthUser != "" {
+			req.SetBasicAuth(w.cfg.BasicAuthUser, w.cfg.BasicAuthPass)
+		}
+
+		resp, err := w.cfg.Client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+		lastErr = fmt.Errorf("loki: push returned status %d", resp.StatusCode)
+	}
+
+	return lastErr
+}
+
+// Flush blocks until the queue is empty, or ctx is done. It waits only for
+// the batching goroutine to take lines off the queue; a partial batch it
+// already holds is not pushed by Flush, but on the next BatchSize or
+// BatchWait trigger, or by Close.
+func (w *Writer) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		for len(w.queue) > 0 {
+			time.Sleep(time.Millisecond)
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops the background push loop after draining any queued lines.
+func (w *Writer) Close() error {
+	w.closeLock.Lock()
+	if w.closed {
+		w.closeLock.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.closeLock.Unlock()
+
+	close(w.queue)
+	w.wg.Wait()
+	return nil
+}
@@ -0,0 +1,269 @@
+package lfshook
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+	"github.com/sirupsen/logrus"
+)
+
+// RotateOptions configures time- and size-based rotation for a single log
+// path. It is translated into the equivalent
+// github.com/lestrrat-go/file-rotatelogs options when the underlying writer
+// is created.
+type RotateOptions struct {
+	// MaxAge is how long to keep a rotated segment around before it is
+	// removed. Zero disables age-based pruning.
+	MaxAge time.Duration
+
+	// RotationTime is how often a new segment is started, e.g. 24*time.Hour
+	// for daily rotation. Zero falls back to file-rotatelogs' own default.
+	RotationTime time.Duration
+
+	// RotationCount caps the number of rotated segments kept on disk,
+	// regardless of age. Zero disables count-based pruning.
+	RotationCount uint
+
+	// RotationSize caps the size in bytes of a single segment before it is
+	// rotated. Zero disables size-based rotation.
+	RotationSize int64
+
+	// Pattern is the strftime-style suffix appended to the base path for
+	// each segment, e.g. ".%Y%m%d" for a daily suffix. Defaults to
+	// ".%Y%m%d%H%M" when empty.
+	Pattern string
+
+	// Compress gzips a segment once it has been rotated out.
+	Compress bool
+}
+
+// PathMap entry used by NewRotateHook: a destination path plus the rotation
+// behavior to apply to it.
+type RotatePathMap map[logrus.Level]RotateConfig
+
+// RotateConfig pairs a path with the RotateOptions to use for it.
+type RotateConfig struct {
+	Path    string
+	Options RotateOptions
+}
+
+// rotatingWriter is a stable indirection in front of the rotatelogs.Writer
+// for a path: hooks hold a *rotatingWriter, not the rotatelogs.Writer
+// itself, so that watchSIGHUP can swap the underlying writer out from under
+// them on reopen instead of handing already-built hooks a dead reference.
+type rotatingWriter struct {
+	path string
+	opts RotateOptions
+
+	lock  sync.Mutex
+	inner io.WriteCloser
+}
+
+func newRotatingWriter(path string, opts RotateOptions) (*rotatingWriter, error) {
+	rw := &rotatingWriter{path: path, opts: opts}
+
+	inner, err := rw.build()
+	if err != nil {
+		return nil, err
+	}
+	rw.inner = inner
+
+	return rw, nil
+}
+
+// build constructs the underlying file-rotatelogs writer for rw.path/rw.opts.
+func (rw *rotatingWriter) build() (io.WriteCloser, error) {
+	rlOpts := []rotatelogs.Option{}
+	if rw.opts.MaxAge > 0 {
+		rlOpts = append(rlOpts, rotatelogs.WithMaxAge(rw.opts.MaxAge))
+	}
+	if rw.opts.RotationTime > 0 {
+		rlOpts = append(rlOpts, rotatelogs.WithRotationTime(rw.opts.RotationTime))
+	}
+	if rw.opts.RotationCount > 0 {
+		rlOpts = append(rlOpts, rotatelogs.WithRotationCount(rw.opts.RotationCount))
+	}
+	if rw.opts.RotationSize > 0 {
+		rlOpts = append(rlOpts, rotatelogs.WithRotationSize(rw.opts.RotationSize))
+	}
+	if rw.opts.Compress {
+		rlOpts = append(rlOpts, rotatelogs.WithHandler(rotatelogs.HandlerFunc(func(e rotatelogs.Event) {
+			if ev, ok := e.(*rotatelogs.FileRotatedEvent); ok {
+				gzipAndRemove(ev.PreviousFile())
+			}
+		})))
+	}
+
+	pattern := rw.opts.Pattern
+	if pattern == "" {
+		pattern = ".%Y%m%d%H%M"
+	}
+
+	return rotatelogs.New(rw.path+pattern, rlOpts...)
+}
+
+// Write implements io.Writer by forwarding to the current underlying
+// writer, which reopen may have swapped out since the last call.
+func (rw *rotatingWriter) Write(p []byte) (int, error) {
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
+	return rw.inner.Write(p)
+}
+
+// reopen closes the current underlying writer and builds a fresh one in its
+// place, so every hook already holding this *rotatingWriter picks up the
+// new file on its next Write without needing to be rebuilt.
+func (rw *rotatingWriter) reopen() error {
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
+
+	if err := rw.inner.Close(); err != nil {
+		log.Println("lfshook: failed to close rotated writer for", rw.path, ":", err)
+	}
+
+	inner, err := rw.build()
+	if err != nil {
+		return err
+	}
+
+	rw.inner = inner
+	return nil
+}
+
+func (rw *rotatingWriter) Close() error {
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
+	return rw.inner.Close()
+}
+
+// writerRegistry lazily builds rotating writers keyed by path and reuses
+// them across Fire calls, so a path is only opened once no matter how many
+// levels share it.
+type writerRegistry struct {
+	lock    sync.Mutex
+	writers map[string]*rotatingWriter
+	sigOnce sync.Once
+}
+
+var rotateRegistry = &writerRegistry{writers: make(map[string]*rotatingWriter)}
+
+func (r *writerRegistry) getOrCreate(path string, opts RotateOptions) (*rotatingWriter, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if w, ok := r.writers[path]; ok {
+		return w, nil
+	}
+
+	w, err := newRotatingWriter(path, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	r.writers[path] = w
+	r.sigOnce.Do(r.watchSIGHUP)
+	return w, nil
+}
+
+// gzipAndRemove compresses path into path+".gz" and removes the original,
+// for RotateOptions.Compress. Failures are logged rather than returned since
+// they happen from inside file-rotatelogs' own rotation goroutine.
+func gzipAndRemove(path string) {
+	if path == "" {
+		return
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		log.Println("lfshook: failed to open rotated segment for compression:", err)
+		return
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		log.Println("lfshook: failed to create compressed segment:", err)
+		return
+	}
+
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		log.Println("lfshook: failed to compress rotated segment:", err)
+		gz.Close()
+		out.Close()
+		return
+	}
+	if err := gz.Close(); err != nil {
+		log.Println("lfshook: failed to finalize compressed segment:", err)
+	}
+	out.Close()
+
+	if err := os.Remove(path); err != nil {
+		log.Println("lfshook: failed to remove uncompressed segment:", err)
+	}
+}
+
+// watchSIGHUP starts a single process-wide goroutine that reopens every
+// registered writer in place whenever SIGHUP arrives, the same
+// logrotate-friendly contract as client9/reopen: an external process
+// truncates or renames a file out from under us, and the next write must
+// land in the new one. Reopening in place (rather than replacing the
+// registry entry) is what lets hooks built before the SIGHUP keep working,
+// since they hold the *rotatingWriter pointer directly. It is started
+// exactly once via sigOnce regardless of how many paths are registered, so
+// a SIGHUP never leaks a goroutine or signal channel.
+func (r *writerRegistry) watchSIGHUP() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+
+	go func() {
+		for range sig {
+			r.lock.Lock()
+			for path, w := range r.writers {
+				if err := w.reopen(); err != nil {
+					log.Println("lfshook: failed to reopen", path, "after SIGHUP:", err)
+				}
+			}
+			r.lock.Unlock()
+		}
+	}()
+}
+
+// NewRotateHook builds a Hook that rotates its destination file(s) according
+// to opts instead of appending to them forever. pathMap may be either a
+// PathMap, in which case opts applies to every path, or a RotatePathMap for
+// per-level rotation settings.
+func NewRotateHook(pathMap interface{}, opts RotateOptions, userFormatter logrus.Formatter) (*lfsHook, error) {
+	writers := WriterMap{}
+
+	switch m := pathMap.(type) {
+	case PathMap:
+		for level, path := range m {
+			w, err := rotateRegistry.getOrCreate(path, opts)
+			if err != nil {
+				return nil, err
+			}
+			writers[level] = w
+		}
+	case RotatePathMap:
+		for level, cfg := range m {
+			w, err := rotateRegistry.getOrCreate(cfg.Path, cfg.Options)
+			if err != nil {
+				return nil, err
+			}
+			writers[level] = w
+		}
+	default:
+		return nil, fmt.Errorf("lfshook: NewRotateHook requires a PathMap or RotatePathMap, got %T", pathMap)
+	}
+
+	return NewHook(writers, userFormatter), nil
+}